@@ -0,0 +1,97 @@
+package generator
+
+import (
+	proto "github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/bliink-io/grpc-graphql-gateway/protoc-gen-graphql/options"
+)
+
+// isStreamingMethod reports whether m is a server-streaming RPC, i.e. a
+// candidate for GetSubscriptions rather than GetQueries/GetMutations. No
+// proto option is needed for this - server_streaming is already part of
+// MethodDescriptorProto - so a Service/Method builder can route a method
+// into .Subscriptions purely from the descriptor, before looking at any
+// graphql_ext option.
+func isStreamingMethod(m *descriptor.MethodDescriptorProto) bool {
+	return m != nil && m.GetServerStreaming()
+}
+
+// methodComplexity returns the weight set on m via
+// option (graphql_ext.complexity) = { weight: N }, or 0 if unset. A
+// Query/Mutation builder feeds this into the generated .Complexity field,
+// which the template only emits when non-zero.
+func methodComplexity(m *descriptor.MethodDescriptorProto) int64 {
+	opts := m.GetOptions()
+	if opts == nil || !proto.HasExtension(opts, options.E_Complexity) {
+		return 0
+	}
+	ext, err := proto.GetExtension(opts, options.E_Complexity)
+	if err != nil {
+		return 0
+	}
+	c, ok := ext.(*options.GraphqlComplexity)
+	if !ok {
+		return 0
+	}
+	return c.GetWeight()
+}
+
+// fieldComplexity returns the weight set on f via
+// [(graphql_ext.field_complexity) = { weight: N }], or 0 if unset.
+func fieldComplexity(f *descriptor.FieldDescriptorProto) int64 {
+	opts := f.GetOptions()
+	if opts == nil || !proto.HasExtension(opts, options.E_FieldComplexity) {
+		return 0
+	}
+	ext, err := proto.GetExtension(opts, options.E_FieldComplexity)
+	if err != nil {
+		return 0
+	}
+	c, ok := ext.(*options.GraphqlComplexity)
+	if !ok {
+		return 0
+	}
+	return c.GetWeight()
+}
+
+// fieldBatch returns the [(graphql_ext.batch) = {...}] option set on f, or
+// nil if the field is resolved verbatim from its parent message rather than
+// through a dataloader. A Field builder uses this to populate .Batch, which
+// the template checks to decide between a plain field and a
+// dataloader.GetOrCreate-backed Resolve.
+func fieldBatch(f *descriptor.FieldDescriptorProto) *options.GraphqlBatch {
+	opts := f.GetOptions()
+	if opts == nil || !proto.HasExtension(opts, options.E_Batch) {
+		return nil
+	}
+	ext, err := proto.GetExtension(opts, options.E_Batch)
+	if err != nil {
+		return nil
+	}
+	b, ok := ext.(*options.GraphqlBatch)
+	if !ok {
+		return nil
+	}
+	return b
+}
+
+// methodAuth returns the option (graphql_ext.auth) = {...} guard set on m,
+// or nil if the method is unguarded. A Query/Mutation builder feeds this
+// into .Auth, which Register<Service>GraphqlHandler uses to wire
+// runtime.RequireRole / runtime.RequireIPWhitelist via UseDirective.
+func methodAuth(m *descriptor.MethodDescriptorProto) *options.GraphqlAuth {
+	opts := m.GetOptions()
+	if opts == nil || !proto.HasExtension(opts, options.E_Auth) {
+		return nil
+	}
+	ext, err := proto.GetExtension(opts, options.E_Auth)
+	if err != nil {
+		return nil
+	}
+	a, ok := ext.(*options.GraphqlAuth)
+	if !ok {
+		return nil
+	}
+	return a
+}
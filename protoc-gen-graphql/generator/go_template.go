@@ -5,10 +5,14 @@ var goTemplate = `
 package {{ .RootPackage.Name }}
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"github.com/ysugimoto/grpc-graphql-gateway/runtime"
+	"github.com/bliink-io/grpc-graphql-gateway/runtime/dataloader"
 	"google.golang.org/grpc"
 
 {{- range .Packages }}
@@ -18,6 +22,10 @@ import (
 
 var _ = json.Marshal
 var _ = json.Unmarshal
+var _ = fmt.Sprint
+var _ context.Context
+var _ = time.Millisecond
+var _ = dataloader.NewSet
 
 {{ range .Types -}}
 var Gql__type_{{ .Name }} = graphql.NewObject(graphql.ObjectConfig{
@@ -32,6 +40,45 @@ var Gql__type_{{ .Name }} = graphql.NewObject(graphql.ObjectConfig{
 			{{- if .Comment }}
 			Description: "{{ .Comment }}",
 			{{- end }}
+			{{- if .Batch }}
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				set, ok := dataloader.FromContext(p.Context)
+				if !ok {
+					return nil, fmt.Errorf("dataloader set not found in context")
+				}
+				loader := dataloader.GetOrCreate(set, "{{ .Batch.Method.Name }}", func(ctx context.Context, keys []{{ .Batch.KeyType }}) ([]interface{}, []error) {
+					client := {{ .Batch.Package }}New{{ .Batch.Service.Name }}Client(xxx__batchConn_{{ .Batch.Service.Name }})
+					resp, err := client.{{ .Batch.Method.Name }}(ctx, &{{ .Batch.RequestType }}{ {{ .Batch.KeyListField }}: keys })
+					if err != nil {
+						errs := make([]error, len(keys))
+						for i := range errs {
+							errs[i] = err
+						}
+						return nil, errs
+					}
+
+					byKey := make(map[{{ .Batch.KeyType }}]interface{}, len(resp.{{ .Batch.ResponseListField }}))
+					for _, item := range resp.{{ .Batch.ResponseListField }} {
+						byKey[item.Get{{ .Batch.KeyAccessor }}()] = item
+					}
+
+					results := make([]interface{}, len(keys))
+					errs := make([]error, len(keys))
+					for i, k := range keys {
+						v, ok := byKey[k]
+						if !ok {
+							errs[i] = fmt.Errorf("key %v not found in {{ .Batch.Method.Name }} response", k)
+							continue
+						}
+						results[i] = v
+					}
+					return results, errs
+				}, {{ .Batch.Wait }}, {{ .Batch.MaxBatchSize }})
+
+				key := p.Source.(*{{ .Batch.ParentType }}).Get{{ .Batch.KeyField }}()
+				return loader.Load(p.Context, key)
+			},
+			{{- end }}
 		},
 {{- end }}
 	},
@@ -77,6 +124,13 @@ type xxx__resolver_{{ .Service.Name }} struct {
 	conn *grpc.ClientConn
 }
 
+// xxx__batchConn_{{ .Service.Name }} is set by Register{{ .Service.Name }}GraphqlHandler
+// to the same *grpc.ClientConn it hands to xxx__resolver_{{ .Service.Name }}, so
+// that batched field resolvers - which are package level types with no
+// access to a particular resolver instance - reuse that connection instead
+// of dialing (and leaking) one of their own.
+var xxx__batchConn_{{ .Service.Name }} *grpc.ClientConn
+
 // GetQueries returns acceptable graphql.Fields for Query.
 func (x *xxx__resolver_{{ .Service.Name }}) GetQueries() graphql.Fields {
 	return graphql.Fields{
@@ -122,6 +176,32 @@ func (x *xxx__resolver_{{ .Service.Name }}) GetQueries() graphql.Fields {
 	}
 }
 
+// GetComplexity returns the complexity weight registered for each query,
+// mutation and exposed field via the (graphql_ext.complexity) /
+// (graphql_ext.field_complexity) proto options, so runtime.ServeMux can enforce
+// SetComplexityLimit against this service's fields.
+func (x *xxx__resolver_{{ .Service.Name }}) GetComplexity() map[string]int {
+	return map[string]int{
+{{- range .Queries }}
+		{{- if .Complexity }}
+		"{{ .QueryName }}": {{ .Complexity }},
+		{{- end }}
+{{- end }}
+{{- range .Mutations }}
+		{{- if .Complexity }}
+		"{{ .MutationName }}": {{ .Complexity }},
+		{{- end }}
+{{- end }}
+{{- range .Types }}
+{{- range .Fields }}
+		{{- if .Complexity }}
+		"{{ .Name }}": {{ .Complexity }},
+		{{- end }}
+{{- end }}
+{{- end }}
+	}
+}
+
 // GetMutations returns acceptable graphql.Fields for Mutation.
 func (x *xxx__resolver_{{ .Service.Name }}) GetMutations() graphql.Fields {
 	return graphql.Fields{
@@ -157,6 +237,67 @@ func (x *xxx__resolver_{{ .Service.Name }}) GetMutations() graphql.Fields {
 	}
 }
 
+// GetSubscriptions returns acceptable graphql.Fields for Subscription. Each
+// entry wraps a server-streaming RPC: Resolve opens the gRPC stream and
+// returns a chan interface{} that runtime.ServeMux drains, forwarding every
+// received message to the subscribed client as a separate data frame.
+func (x *xxx__resolver_{{ .Service.Name }}) GetSubscriptions() graphql.Fields {
+	return graphql.Fields{
+{{- range .Subscriptions }}
+		"{{ .SubscriptionName }}": &graphql.Field{
+			Type: {{ .SubscriptionType }},
+			{{- if .Comment }}
+			Description: "{{ .Comment }}",
+			{{- end }}
+			{{- if .Args }}
+			Args: graphql.FieldConfigArgument{
+			{{- range .Args }}
+				"{{ .Name }}": &graphql.ArgumentConfig{
+					Type: {{ .FieldType $.RootPackage.Path }},
+					{{- if .Comment }}
+					Description: "{{ .Comment }}",
+					{{- end }}
+				},
+			{{- end }}
+			},
+			{{- end }}
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				var req *{{ .RequestType }}
+				if err := runtime.MarshalRequest(p.Args, req); err != nil {
+					return nil, err
+				}
+				client := {{ .Package }}New{{ .Service.Name }}Client(x.conn)
+				stream, err := client.{{ .Method.Name }}(p.Context, req)
+				if err != nil {
+					return nil, err
+				}
+
+				ch := make(chan interface{})
+				go func() {
+					defer close(ch)
+					for {
+						resp, err := stream.Recv()
+						if err != nil {
+							return
+						}
+						select {
+						case <-p.Context.Done():
+							return
+						{{- if .Expose }}
+						case ch <- resp.Get{{ .Expose }}():
+						{{- else }}
+						case ch <- resp:
+						{{- end }}
+						}
+					}
+				}()
+				return ch, nil
+			},
+		},
+{{- end }}
+	}
+}
+
 // Register package divided graphql handler "without" *grpc.ClientConn,
 // therefore gRPC connection will be opened and closed automatically.
 // Occasionally you worried about open/close performance for each handling graphql request,
@@ -184,6 +325,31 @@ func Register{{ .Service.Name }}GraphqlHandler(mux *runtime.ServeMux, conn *grpc
 			return
 		}
 	}
+	xxx__batchConn_{{ .Service.Name }} = conn
 	mux.AddHandler(&xxx__resolver_{{ .Service.Name }}{conn})
+
+	// Wire up per-method guards declared via (graphql_ext.auth). Errors
+	// surface through the same UseDirective machinery used by
+	// hand-registered directives, as structured GraphqlError values.
+{{- range .Queries }}
+	{{- if .Auth }}
+	{{- if .Auth.Role }}
+	mux.UseDirective("{{ .QueryName }}", "auth", runtime.RequireRole("{{ .Auth.Role }}"))
+	{{- end }}
+	{{- if .Auth.Whitelist }}
+	mux.UseDirective("{{ .QueryName }}", "whitelist", runtime.RequireIPWhitelist({{ range .Auth.Whitelist }}"{{ . }}", {{ end }}))
+	{{- end }}
+	{{- end }}
+{{- end }}
+{{- range .Mutations }}
+	{{- if .Auth }}
+	{{- if .Auth.Role }}
+	mux.UseDirective("{{ .MutationName }}", "auth", runtime.RequireRole("{{ .Auth.Role }}"))
+	{{- end }}
+	{{- if .Auth.Whitelist }}
+	mux.UseDirective("{{ .MutationName }}", "whitelist", runtime.RequireIPWhitelist({{ range .Auth.Whitelist }}"{{ . }}", {{ end }}))
+	{{- end }}
+	{{- end }}
+{{- end }}
 	return
 }`
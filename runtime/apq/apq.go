@@ -0,0 +1,92 @@
+// Package apq implements Apollo-style automatic persisted queries for
+// runtime.ServeMux: clients send a query's sha256 hash instead of its full
+// text, falling back to sending the text once on a cache miss.
+package apq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// ErrNotRegistered is returned by a Cache that only accepts lookups against
+// a fixed set of hashes, e.g. Registry, when asked to store a new query.
+var ErrNotRegistered = errors.New("apq: query is not part of the persisted query registry")
+
+// Cache resolves a query's sha256 hash to its text and, where supported,
+// stores newly-seen queries against their hash. Set runtime.ServeMux's
+// cache with runtime.WithPersistedQueryCache.
+type Cache interface {
+	Get(hash string) (query string, ok bool)
+	Set(hash string, query string) error
+}
+
+// Hash returns the lowercase hex sha256 digest of query, matching the
+// sha256Hash clients send in the extensions.persistedQuery object.
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+type lruCache struct {
+	cache *lru.Cache
+}
+
+// NewLRUCache returns a Cache backed by an in-memory LRU of the given size.
+// This is the default used by ServeMux when WithPersistedQueryCache is
+// never called and a request includes extensions.persistedQuery.
+func NewLRUCache(size int) (Cache, error) {
+	c, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &lruCache{cache: c}, nil
+}
+
+func (c *lruCache) Get(hash string) (string, bool) {
+	v, ok := c.cache.Get(hash)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
+
+func (c *lruCache) Set(hash string, query string) error {
+	c.cache.Add(hash, query)
+	return nil
+}
+
+// Registry is a Cache locked down to a fixed manifest of hash -> query
+// pairs loaded at startup, useful to pin production to a known set of
+// operations. Writes always fail with ErrNotRegistered.
+type Registry struct {
+	queries map[string]string
+}
+
+// LoadManifest reads a JSON object of {"<sha256Hash>": "<query>"} pairs
+// from path and returns a Registry serving only those queries.
+func LoadManifest(path string) (*Registry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make(map[string]string)
+	if err := json.Unmarshal(b, &queries); err != nil {
+		return nil, err
+	}
+	return &Registry{queries: queries}, nil
+}
+
+func (r *Registry) Get(hash string) (string, bool) {
+	query, ok := r.queries[hash]
+	return query, ok
+}
+
+func (r *Registry) Set(hash string, query string) error {
+	return ErrNotRegistered
+}
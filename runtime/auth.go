@@ -0,0 +1,178 @@
+package runtime
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// roleContextKey is the context key under which the authenticated
+// caller's role is stored by an application's own authentication
+// middleware, for RequireRole to read back.
+type roleContextKey struct{}
+
+// ContextWithRole returns a context carrying role, so that a RequireRole
+// directive further down the chain can see who's calling. Call this from
+// whatever middleware authenticates the request (a JWT verifier, ...);
+// StaticToken does not call it.
+func ContextWithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleContextKey{}, role)
+}
+
+// RoleFromContext returns the role stored by ContextWithRole, if any.
+func RoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(roleContextKey{}).(string)
+	return role, ok
+}
+
+// ipWhitelist parses a set of CIDRs once and checks addresses against them.
+type ipWhitelist struct {
+	nets []*net.IPNet
+}
+
+func newIPWhitelist(cidrs []string) (*ipWhitelist, error) {
+	w := &ipWhitelist{nets: make([]*net.IPNet, 0, len(cidrs))}
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			cidr += "/32"
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		w.nets = append(w.nets, n)
+	}
+	return w, nil
+}
+
+func (w *ipWhitelist) allows(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range w.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedProxies restricts which immediate-hop RemoteAddrs clientIP will
+// trust to set X-Forwarded-For. Until SetTrustedProxies is called, it is
+// nil and X-Forwarded-For is never honored, since any client can set that
+// header themselves.
+var trustedProxies *ipWhitelist
+
+// SetTrustedProxies declares the reverse proxies clientIP should trust to
+// set X-Forwarded-For - without this, any caller could spoof
+// X-Forwarded-For to defeat IPWhitelist/RequireIPWhitelist, so
+// X-Forwarded-For is ignored until the immediate RemoteAddr is in cidrs.
+func SetTrustedProxies(cidrs ...string) error {
+	w, err := newIPWhitelist(cidrs)
+	if err != nil {
+		return err
+	}
+	trustedProxies = w
+	return nil
+}
+
+// clientIP returns the request's originating IP. RemoteAddr is used unless
+// it belongs to a proxy registered via SetTrustedProxies, in which case
+// X-Forwarded-For's first hop is trusted instead, since this gateway is
+// commonly deployed behind a reverse proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if trustedProxies != nil && trustedProxies.allows(host) {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+	return host
+}
+
+// IPWhitelist rejects any request whose client IP doesn't fall within one
+// of cidrs. Register it mux-wide with ServeMux.Use; for a single method
+// use RequireIPWhitelist with UseDirective instead.
+func IPWhitelist(cidrs ...string) MiddlewareFunc {
+	w, err := newIPWhitelist(cidrs)
+	return func(ctx context.Context, w2 http.ResponseWriter, r *http.Request) (context.Context, error) {
+		if err != nil {
+			return ctx, err
+		}
+		if !w.allows(clientIP(r)) {
+			return ctx, &MiddlewareError{
+				Message: "client IP is not allowed",
+				Code:    "IP_NOT_ALLOWED",
+			}
+		}
+		return ctx, nil
+	}
+}
+
+// RequireIPWhitelist is the per-method equivalent of IPWhitelist, meant to
+// be registered against a single query/mutation via UseDirective - this is
+// what the generator emits for a method carrying a (graphql_ext.auth)
+// whitelist option.
+func RequireIPWhitelist(cidrs ...string) GraphQLMiddlewareFunc {
+	w, err := newIPWhitelist(cidrs)
+	return func(ctx context.Context, r *http.Request, method string) error {
+		if err != nil {
+			return err
+		}
+		if !w.allows(clientIP(r)) {
+			return &MiddlewareError{
+				Message: "client IP is not allowed to call " + method,
+				Code:    "IP_NOT_ALLOWED",
+			}
+		}
+		return nil
+	}
+}
+
+// StaticToken is a "poor man's auth" MiddlewareFunc: it requires header to
+// equal token exactly, and is otherwise a no-op - no role is attached to
+// the context, since a shared token has no notion of identity. The
+// comparison runs in constant time so a caller can't use response timing
+// to brute-force the token one byte at a time.
+func StaticToken(header string, token string) MiddlewareFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		got := r.Header.Get(header)
+		if len(got) != len(token) || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return ctx, &MiddlewareError{
+				Message: "missing or invalid " + header,
+				Code:    "UNAUTHENTICATED",
+			}
+		}
+		return ctx, nil
+	}
+}
+
+// RequireRole is a GraphQLMiddlewareFunc, meant to be registered against a
+// single query/mutation via UseDirective, that enforces the caller's role
+// (as attached to the context with ContextWithRole by an earlier
+// MiddlewareFunc) matches role exactly. This is what the generator emits
+// for a method carrying a (graphql_ext.auth) role option.
+func RequireRole(role string) GraphQLMiddlewareFunc {
+	return func(ctx context.Context, r *http.Request, method string) error {
+		got, ok := RoleFromContext(ctx)
+		if !ok {
+			return &MiddlewareError{
+				Message: "no authenticated role found for " + method,
+				Code:    "UNAUTHENTICATED",
+			}
+		}
+		if got != role {
+			return &MiddlewareError{
+				Message: "role " + got + " is not allowed to call " + method,
+				Code:    "FORBIDDEN",
+			}
+		}
+		return nil
+	}
+}
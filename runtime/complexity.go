@@ -0,0 +1,186 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// defaultFieldComplexity is the weight assigned to a field that has no
+// registered complexity, e.g. one the generator didn't see a
+// `(graphql_ext.field_complexity)` option for.
+const defaultFieldComplexity = 1
+
+// multiplierArgs lists the pagination-style arguments that, when present on
+// a field, scale the complexity of that field's children. This mirrors how
+// gqlgen derives multipliers for connection-shaped fields.
+var multiplierArgs = []string{"first", "last", "limit"}
+
+// ComplexityFunc lets callers override how a field's complexity is derived
+// from its name, its arguments and its children's total complexity. method is
+// the field name as it appears in the operation; args are the field's
+// resolved arguments. The registered weight is not passed in, and the
+// pagination multiplier is not applied to the returned value.
+type ComplexityFunc func(ctx context.Context, method string, args map[string]interface{}, childComplexity int) int
+
+// ComplexityHandler is an optional interface a GraphqlHandler may
+// additionally implement to register per-field complexity weights emitted
+// by the generator from `(graphql_ext.complexity)` / `(graphql_ext.field_complexity)`
+// proto options.
+type ComplexityHandler interface {
+	GetComplexity() map[string]int
+}
+
+// SetComplexityLimit caps the total complexity a single operation may
+// accumulate. A limit of 0 (the default) disables the check.
+func (s *ServeMux) SetComplexityLimit(limit int) *ServeMux {
+	s.complexityLimit = limit
+	return s
+}
+
+// SetComplexityFunc overrides the default complexity * max(1, children)
+// formula used when walking an operation.
+func (s *ServeMux) SetComplexityFunc(fn ComplexityFunc) *ServeMux {
+	s.complexityFunc = fn
+	return s
+}
+
+// checkComplexity parses query, walks it against the registered field
+// weights and returns an error once the total exceeds s.complexityLimit.
+// It is a no-op when no limit has been configured.
+func (s *ServeMux) checkComplexity(ctx context.Context, query string, variables map[string]interface{}) error {
+	if s.complexityLimit <= 0 {
+		return nil
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		// Malformed queries are reported by graphql.Do itself; don't
+		// duplicate that error here.
+		return nil
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[frag.Name.Value] = frag
+		}
+	}
+
+	total := 0
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok || op.SelectionSet == nil {
+			continue
+		}
+		total += s.walkSelectionSet(ctx, op.SelectionSet, variables, fragments)
+	}
+
+	if total > s.complexityLimit {
+		return &MiddlewareError{
+			Message: "query complexity exceeds the configured limit",
+			Code:    "COMPLEXITY_LIMIT_EXCEEDED",
+		}
+	}
+	return nil
+}
+
+// walkSelectionSet accumulates the complexity of set, resolving
+// *ast.FragmentSpread and *ast.InlineFragment selections against fragments
+// rather than skipping them - an operation that hides its expensive fields
+// behind a fragment would otherwise score 0 regardless of the limit.
+func (s *ServeMux) walkSelectionSet(ctx context.Context, set *ast.SelectionSet, variables map[string]interface{}, fragments map[string]*ast.FragmentDefinition) int {
+	if set == nil {
+		return 0
+	}
+
+	total := 0
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			name := sel.Name.Value
+			args := resolveArguments(sel.Arguments, variables)
+			childComplexity := s.walkSelectionSet(ctx, sel.SelectionSet, variables, fragments)
+
+			var fieldComplexity int
+			if s.complexityFunc != nil {
+				fieldComplexity = s.complexityFunc(ctx, name, args, childComplexity)
+			} else {
+				weight := s.complexityWeightFor(name)
+				if weight == 0 {
+					weight = defaultFieldComplexity
+				}
+				children := childComplexity * multiplierFor(args)
+				if children < 1 {
+					children = 1
+				}
+				fieldComplexity = weight * children
+			}
+			total += fieldComplexity
+
+		case *ast.InlineFragment:
+			total += s.walkSelectionSet(ctx, sel.SelectionSet, variables, fragments)
+
+		case *ast.FragmentSpread:
+			if frag, ok := fragments[sel.Name.Value]; ok {
+				total += s.walkSelectionSet(ctx, frag.SelectionSet, variables, fragments)
+			}
+		}
+	}
+	return total
+}
+
+// multiplierFor returns the pagination-derived multiplier for a field's
+// resolved arguments, defaulting to 1 when none of multiplierArgs are set.
+func multiplierFor(args map[string]interface{}) int {
+	for _, name := range multiplierArgs {
+		v, ok := args[name]
+		if !ok {
+			continue
+		}
+		if n, ok := toInt(v); ok && n > 1 {
+			return n
+		}
+	}
+	return 1
+}
+
+func resolveArguments(args []*ast.Argument, variables map[string]interface{}) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		resolved[arg.Name.Value] = resolveValue(arg.Value, variables)
+	}
+	return resolved
+}
+
+func resolveValue(v ast.Value, variables map[string]interface{}) interface{} {
+	switch val := v.(type) {
+	case *ast.IntValue:
+		return val.Value
+	case *ast.Variable:
+		return variables[val.Name.Value]
+	default:
+		return nil
+	}
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	case string:
+		var out int
+		if _, err := fmt.Sscan(n, &out); err == nil {
+			return out, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,107 @@
+// Package dataloader collapses the N+1 gRPC calls a naively-generated
+// nested resolver makes into one batched call per GraphQL operation. It is
+// a minimal, generic reimplementation of the pattern made popular by
+// graph-gophers/dataloader and Facebook's original dataloader.js.
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FetchFunc batches a set of keys observed within a single wait window
+// into one call, returning one value (or error) per key, in the same
+// order as keys.
+type FetchFunc[K comparable, V any] func(ctx context.Context, keys []K) ([]V, []error)
+
+// Loader coalesces Load calls made within wait of the first Load of a batch
+// (or until maxBatch distinct keys have piled up) into a single FetchFunc
+// call, fetching each distinct key once.
+type Loader[K comparable, V any] struct {
+	fetch    FetchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	batch *batch[K, V]
+}
+
+// NewLoader creates a Loader. wait is how long to hold a batch open
+// waiting for more keys; maxBatch caps how many keys a single call to
+// fetch may receive (0 means unbounded).
+func NewLoader[K comparable, V any](fetch FetchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, wait: wait, maxBatch: maxBatch}
+}
+
+type batch[K comparable, V any] struct {
+	keys    []K
+	index   map[K]int
+	results []V
+	errs    []error
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newBatch[K comparable, V any]() *batch[K, V] {
+	return &batch[K, V]{index: make(map[K]int), done: make(chan struct{})}
+}
+
+// Load returns the value for key, dispatching (or joining) a batch as
+// needed. Concurrent Load calls for the same key within the same batch
+// share one slot in the underlying fetch.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	if l.batch == nil {
+		l.batch = newBatch[K, V]()
+		go l.scheduleDispatch(ctx, l.batch)
+	}
+	b := l.batch
+
+	pos, ok := b.index[key]
+	if !ok {
+		pos = len(b.keys)
+		b.index[key] = pos
+		b.keys = append(b.keys, key)
+	}
+
+	full := l.maxBatch > 0 && len(b.keys) >= l.maxBatch
+	if full {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	if full {
+		l.dispatch(ctx, b)
+	}
+
+	<-b.done
+
+	var zero V
+	if pos < len(b.errs) && b.errs[pos] != nil {
+		return zero, b.errs[pos]
+	}
+	if pos >= len(b.results) {
+		return zero, nil
+	}
+	return b.results[pos], nil
+}
+
+func (l *Loader[K, V]) scheduleDispatch(ctx context.Context, b *batch[K, V]) {
+	time.Sleep(l.wait)
+
+	l.mu.Lock()
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	l.dispatch(ctx, b)
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	b.once.Do(func() {
+		b.results, b.errs = l.fetch(ctx, b.keys)
+		close(b.done)
+	})
+}
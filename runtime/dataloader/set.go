@@ -0,0 +1,51 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type ctxKey struct{}
+
+// Set holds every Loader created for a single GraphQL operation, keyed by
+// name (typically the batch RPC's method name). runtime.ServeMux installs
+// one empty Set into the request context per ServeHTTP call so that
+// loaders - and therefore batching - never leak across requests.
+type Set struct {
+	mu      sync.Mutex
+	loaders map[string]interface{}
+}
+
+// NewSet returns an empty Set.
+func NewSet() *Set {
+	return &Set{loaders: make(map[string]interface{})}
+}
+
+// WithSet returns a context carrying s, retrievable later with FromContext.
+func WithSet(ctx context.Context, s *Set) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// FromContext returns the Set installed by WithSet, if any.
+func FromContext(ctx context.Context) (*Set, bool) {
+	s, ok := ctx.Value(ctxKey{}).(*Set)
+	return s, ok
+}
+
+// GetOrCreate returns the Loader registered under name in s, creating it
+// via fetch/wait/maxBatch the first time it's asked for. Generated
+// resolvers call this once per field invocation; only the first call
+// within an operation actually builds the Loader.
+func GetOrCreate[K comparable, V any](s *Set, name string, fetch FetchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.loaders[name]; ok {
+		return existing.(*Loader[K, V])
+	}
+
+	loader := NewLoader(fetch, wait, maxBatch)
+	s.loaders[name] = loader
+	return loader
+}
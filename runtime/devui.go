@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// GraphiQL returns an http.Handler serving the GraphiQL IDE, pointed at
+// endpoint (the path ServeMux itself is mounted on). Mount it alongside
+// the mux, e.g. `http.Handle("/graphiql", runtime.GraphiQL("/graphql"))`.
+func GraphiQL(endpoint string) http.Handler {
+	page := fmt.Sprintf(graphiqlTemplate, endpoint)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page)) // nolint: errcheck
+	})
+}
+
+// Playground returns an http.Handler serving the GraphQL Playground IDE,
+// pointed at endpoint (the path ServeMux itself is mounted on). Mount it
+// alongside the mux, e.g.
+// `http.Handle("/playground", runtime.Playground("/graphql"))`.
+func Playground(endpoint string) http.Handler {
+	page := fmt.Sprintf(playgroundTemplate, endpoint)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page)) // nolint: errcheck
+	})
+}
+
+const graphiqlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <title>GraphiQL</title>
+  <style>body { margin: 0; height: 100%%; } #graphiql { height: 100vh; }</style>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql/graphiql.min.css" />
+</head>
+<body>
+  <div id="graphiql">Loading GraphiQL...</div>
+  <script crossorigin src="https://unpkg.com/react/umd/react.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/react-dom/umd/react-dom.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphiql/graphiql.min.js"></script>
+  <script>
+    ReactDOM.render(
+      React.createElement(GraphiQL, {
+        fetcher: GraphiQL.createFetcher({ url: %q }),
+      }),
+      document.getElementById('graphiql'),
+    );
+  </script>
+</body>
+</html>
+`
+
+const playgroundTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <title>GraphQL Playground</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphql-playground-react/build/static/css/index.css" />
+  <script src="https://unpkg.com/graphql-playground-react/build/static/js/middleware.js"></script>
+</head>
+<body>
+  <div id="root"></div>
+  <script>
+    window.addEventListener('load', function () {
+      GraphQLPlayground.init(document.getElementById('root'), {
+        endpoint: %q,
+      });
+    });
+  </script>
+</body>
+</html>
+`
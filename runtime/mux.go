@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"encoding/json"
 	"net/http"
 
+	"github.com/bliink-io/grpc-graphql-gateway/runtime/apq"
+	"github.com/bliink-io/grpc-graphql-gateway/runtime/dataloader"
+	"github.com/gorilla/websocket"
 	"github.com/graphql-go/graphql"
 	"google.golang.org/grpc"
 )
@@ -34,14 +38,58 @@ type ServeMux struct {
 	ErrorHandler       GraphqlErrorHandler
 
 	handlers []GraphqlHandler
+
+	// wsUpgrader is used to promote a /graphql request into a GraphQL
+	// subscription connection. See WithWebsocketUpgrader.
+	wsUpgrader *websocket.Upgrader
+
+	// complexityLimit, complexityFunc and fieldComplexity back the query
+	// complexity check. See SetComplexityLimit / SetComplexityFunc.
+	complexityLimit int
+	complexityFunc  ComplexityFunc
+	fieldComplexity map[string]int
+
+	// persistedQueryCache backs automatic persisted queries, defaulting to
+	// an in-memory LRU so resolvePersistedQuery never has to lazily
+	// initialize it (and race with concurrent requests) on first use. See
+	// WithPersistedQueryCache.
+	persistedQueryCache apq.Cache
+
+	// sdlPath, when set via HandleSDL, is the request path ServeHTTP serves
+	// the schema's SDL from instead of executing a GraphQL operation.
+	sdlPath string
+
+	// schemaMu guards schema, schemaErr, schemaVersion, schemaClosers and
+	// fieldComplexity: ServeHTTP calls getSchema/checkComplexity
+	// concurrently per request, while a cache miss rebuilds all of them.
+	schemaMu sync.RWMutex
+
+	// schema, schemaErr and handlerVersion cache the built schema across
+	// requests: AddHandler bumps handlerVersion, and getSchema only pays
+	// for CreateConnection + graphql.NewSchema again once the handler set
+	// has actually changed since the last build.
+	schema         graphql.Schema
+	schemaErr      error
+	schemaVersion  int
+	handlerVersion int
+
+	// schemaClosers closes the connections backing the currently cached
+	// schema's resolvers. It is only invoked when the schema is about to be
+	// rebuilt (or the handler set changes again), so a connection stays
+	// open for as long as the schema referencing it is actually served.
+	schemaClosers []func()
 }
 
 // NewServeMux creates ServeMux pointer
 func NewServeMux(ms ...MiddlewareFunc) *ServeMux {
+	// defaultPersistedQueryCacheSize is always > 0, so this never errors.
+	cache, _ := apq.NewLRUCache(defaultPersistedQueryCacheSize)
 	return &ServeMux{
-		middlewares:        ms,
-		handlers:           make([]GraphqlHandler, 0),
-		graphQLMiddlewares: make(map[string]map[string]GraphQLMiddlewareFunc),
+		middlewares:         ms,
+		handlers:            make([]GraphqlHandler, 0),
+		graphQLMiddlewares:  make(map[string]map[string]GraphQLMiddlewareFunc),
+		schemaVersion:       -1,
+		persistedQueryCache: cache,
 	}
 }
 
@@ -51,6 +99,7 @@ func (s *ServeMux) AddHandler(h GraphqlHandler) error {
 		return err
 	}
 	s.handlers = append(s.handlers, h)
+	s.handlerVersion++
 	return nil
 }
 
@@ -103,50 +152,89 @@ func (s *ServeMux) UseDirective(method string, directive string, ms GraphQLMiddl
 	return s
 }
 
-// ServeHTTP implements http.Handler
-func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	for _, m := range s.middlewares {
-		var err error
-		ctx, err = m(ctx, w, r)
-		if err != nil {
-			ge := GraphqlError{}
-			if me, ok := err.(*MiddlewareError); ok {
-				ge.Message = me.Message
-				ge.Extensions = map[string]interface{}{
-					"code": me.Code,
-				}
-			} else {
-				ge.Message = err.Error()
-				ge.Extensions = map[string]interface{}{
-					"code": "MIDDLEWARE_ERROR",
-				}
-			}
-			respondResult(w, &graphql.Result{
-				Errors: []GraphqlError{ge},
-			})
-			return
-		}
+// HandleSDL makes ServeHTTP respond to path with the merged schema's SDL
+// instead of executing a GraphQL operation, so e.g. `curl /graphql/schema`
+// can feed a consumer's codegen. See SchemaSDL.
+func (s *ServeMux) HandleSDL(path string) *ServeMux {
+	s.sdlPath = path
+	return s
+}
+
+// SchemaSDL builds the schema from every registered handler, same as
+// ServeHTTP, and serializes it to SDL. The build is cached the same way as
+// ServeHTTP's: it's only redone when a handler has been added since the
+// last call.
+func (s *ServeMux) SchemaSDL() (string, error) {
+	schema, err := s.getSchema(context.Background())
+	if err != nil {
+		return "", err
+	}
+	return printSchema(schema), nil
+}
+
+// complexityWeightFor returns the registered complexity weight for method,
+// guarding the read against a concurrent schema rebuild.
+func (s *ServeMux) complexityWeightFor(method string) int {
+	s.schemaMu.RLock()
+	defer s.schemaMu.RUnlock()
+	return s.fieldComplexity[method]
+}
+
+// getSchema returns the schema built from the currently registered
+// handlers, rebuilding it only when the handler set has changed since the
+// last call - graphql.NewSchema and the CreateConnection round-trip it
+// takes to get there are otherwise identical work on every request.
+func (s *ServeMux) getSchema(ctx context.Context) (graphql.Schema, error) {
+	s.schemaMu.RLock()
+	if s.schemaVersion == s.handlerVersion {
+		schema, err := s.schema, s.schemaErr
+		s.schemaMu.RUnlock()
+		return schema, err
+	}
+	s.schemaMu.RUnlock()
+
+	s.schemaMu.Lock()
+	defer s.schemaMu.Unlock()
+	// Another request may have rebuilt the schema while we were waiting
+	// for the write lock.
+	if s.schemaVersion == s.handlerVersion {
+		return s.schema, s.schemaErr
+	}
+
+	// The connections backing the schema we're about to replace were kept
+	// open for its entire lifetime; close them now that it's being
+	// rebuilt, not when buildSchema returns.
+	for _, closer := range s.schemaClosers {
+		closer()
 	}
+	s.schemaClosers = nil
+
+	s.schema, s.schemaErr = s.buildSchema(ctx)
+	s.schemaVersion = s.handlerVersion
+	return s.schema, s.schemaErr
+}
 
+// buildSchema gathers queries, mutations and subscriptions from every
+// registered handler and compiles them into a single graphql.Schema. Called
+// with schemaMu held. The connections it opens are kept alive in
+// s.schemaClosers for as long as the returned schema is cached - closing
+// them here would leave the schema's resolvers holding a dead connection
+// for every request served before the next rebuild.
+func (s *ServeMux) buildSchema(ctx context.Context) (graphql.Schema, error) {
 	queries := graphql.Fields{}
 	mutations := graphql.Fields{}
+	subscriptions := graphql.Fields{}
+	fieldComplexity := make(map[string]int)
+	var closers []func()
 	for _, h := range s.handlers {
 		c, closer, err := h.CreateConnection(ctx)
 		if err != nil {
-			respondResult(w, &graphql.Result{
-				Errors: []GraphqlError{
-					{
-						Message: "Failed to create grpc connection: " + err.Error(),
-						Extensions: map[string]interface{}{
-							"code": "GRPC_CONNECT_ERROR",
-						},
-					},
-				},
-			})
-			return
+			for _, cl := range closers {
+				cl()
+			}
+			return graphql.Schema{}, fmt.Errorf("Failed to create grpc connection: %s", err)
 		}
-		defer closer()
+		closers = append(closers, closer)
 
 		for k, v := range h.GetQueries(c) {
 			queries[k] = v
@@ -154,7 +242,19 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		for k, v := range h.GetMutations(c) {
 			mutations[k] = v
 		}
+		if sh, ok := h.(GraphqlSubscriptionHandler); ok {
+			for k, v := range sh.GetSubscriptions() {
+				subscriptions[k] = v
+			}
+		}
+		if ch, ok := h.(ComplexityHandler); ok {
+			for k, v := range ch.GetComplexity() {
+				fieldComplexity[k] = v
+			}
+		}
 	}
+	s.schemaClosers = closers
+	s.fieldComplexity = fieldComplexity
 
 	schemaConfig := graphql.SchemaConfig{}
 	if len(queries) > 0 {
@@ -169,8 +269,54 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			Fields: mutations,
 		})
 	}
+	if len(subscriptions) > 0 {
+		schemaConfig.Subscription = graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Subscription",
+			Fields: subscriptions,
+		})
+	}
+
+	return graphql.NewSchema(schemaConfig)
+}
+
+// ServeHTTP implements http.Handler
+func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.sdlPath != "" && r.URL.Path == s.sdlPath {
+		sdl, err := s.SchemaSDL()
+		if err != nil {
+			http.Error(w, "Failed to build schema: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(sdl)) // nolint: errcheck
+		return
+	}
 
-	schema, err := graphql.NewSchema(schemaConfig)
+	ctx := dataloader.WithSet(r.Context(), dataloader.NewSet())
+	for _, m := range s.middlewares {
+		var err error
+		ctx, err = m(ctx, w, r)
+		if err != nil {
+			ge := GraphqlError{}
+			if me, ok := err.(*MiddlewareError); ok {
+				ge.Message = me.Message
+				ge.Extensions = map[string]interface{}{
+					"code": me.Code,
+				}
+			} else {
+				ge.Message = err.Error()
+				ge.Extensions = map[string]interface{}{
+					"code": "MIDDLEWARE_ERROR",
+				}
+			}
+			respondResult(w, &graphql.Result{
+				Errors: []GraphqlError{ge},
+			})
+			return
+		}
+	}
+
+	schema, err := s.getSchema(ctx)
 	if err != nil {
 		respondResult(w, &graphql.Result{
 			Errors: []GraphqlError{
@@ -185,6 +331,11 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if schema.SubscriptionType() != nil && isWebsocketUpgrade(r) {
+		s.serveSubscription(schema, w, r, ctx)
+		return
+	}
+
 	req, err := parseRequest(r)
 	if err != nil {
 		respondResult(w, &graphql.Result{
@@ -200,12 +351,48 @@ func (s *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	methodNames := make([]string, 0, len(queries)+len(mutations))
-	for method := range queries {
-		methodNames = append(methodNames, method)
+	if err := s.resolvePersistedQuery(req); err != nil {
+		ge := GraphqlError{}
+		if me, ok := err.(*MiddlewareError); ok {
+			ge.Message = me.Message
+			ge.Extensions = map[string]interface{}{
+				"code": me.Code,
+			}
+		} else {
+			ge.Message = err.Error()
+		}
+		respondResult(w, &graphql.Result{
+			Errors: []GraphqlError{ge},
+		})
+		return
 	}
-	for method := range mutations {
-		methodNames = append(methodNames, method)
+
+	if err := s.checkComplexity(ctx, req.Query, req.Variables); err != nil {
+		ge := GraphqlError{}
+		if me, ok := err.(*MiddlewareError); ok {
+			ge.Message = me.Message
+			ge.Extensions = map[string]interface{}{
+				"code": me.Code,
+			}
+		} else {
+			ge.Message = err.Error()
+		}
+		respondResult(w, &graphql.Result{
+			Errors: []GraphqlError{ge},
+		})
+		return
+	}
+
+	var methodNames []string
+	if schema.QueryType() != nil {
+		for method := range schema.QueryType().Fields() {
+			methodNames = append(methodNames, method)
+		}
+	}
+	if schema.MutationType() != nil {
+		for method := range schema.MutationType().Fields() {
+			methodNames = append(methodNames, method)
+		}
 	}
 
 	for _, method := range methodNames {
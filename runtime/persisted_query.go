@@ -0,0 +1,80 @@
+package runtime
+
+import "github.com/bliink-io/grpc-graphql-gateway/runtime/apq"
+
+// defaultPersistedQueryCacheSize is used for the LRU cache NewServeMux
+// installs by default, used until WithPersistedQueryCache overrides it.
+const defaultPersistedQueryCacheSize = 1000
+
+// WithPersistedQueryCache configures the apq.Cache ServeMux uses to resolve
+// automatic persisted queries. Pass an apq.Registry built from
+// apq.LoadManifest to lock a deployment down to a fixed set of operations.
+func (s *ServeMux) WithPersistedQueryCache(c apq.Cache) *ServeMux {
+	s.persistedQueryCache = c
+	return s
+}
+
+// resolvePersistedQuery implements the APQ protocol: a request carrying
+// only a hash is resolved against the cache, a request carrying both hash
+// and query is validated and stored, and a request without
+// extensions.persistedQuery passes through untouched - unless the cache is
+// an apq.Registry, in which case the deployment is locked down to its
+// manifest and a plain query is rejected outright.
+func (s *ServeMux) resolvePersistedQuery(req *GraphqlRequest) error {
+	ext, ok := req.Extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		if s.lockedToPersistedQueryRegistry() {
+			return &MiddlewareError{
+				Message: "only registered persisted queries are accepted",
+				Code:    "PERSISTED_QUERY_REQUIRED",
+			}
+		}
+		return nil
+	}
+	hash, _ := ext["sha256Hash"].(string)
+	if hash == "" {
+		if s.lockedToPersistedQueryRegistry() {
+			return &MiddlewareError{
+				Message: "only registered persisted queries are accepted",
+				Code:    "PERSISTED_QUERY_REQUIRED",
+			}
+		}
+		return nil
+	}
+
+	if req.Query == "" {
+		query, ok := s.persistedQueryCache.Get(hash)
+		if !ok {
+			return &MiddlewareError{
+				Message: "PersistedQueryNotFound",
+				Code:    "PERSISTED_QUERY_NOT_FOUND",
+			}
+		}
+		req.Query = query
+		return nil
+	}
+
+	if apq.Hash(req.Query) != hash {
+		return &MiddlewareError{
+			Message: "provided sha256Hash does not match the query",
+			Code:    "PERSISTED_QUERY_MISMATCH",
+		}
+	}
+
+	if err := s.persistedQueryCache.Set(hash, req.Query); err != nil {
+		return &MiddlewareError{
+			Message: "PersistedQueryNotFound",
+			Code:    "PERSISTED_QUERY_NOT_FOUND",
+		}
+	}
+	return nil
+}
+
+// lockedToPersistedQueryRegistry reports whether the configured cache is an
+// apq.Registry - a manifest of pre-approved operations that never accepts
+// new queries - in which case a request skipping extensions.persistedQuery
+// entirely must be rejected rather than let through unchecked.
+func (s *ServeMux) lockedToPersistedQueryRegistry() bool {
+	_, ok := s.persistedQueryCache.(*apq.Registry)
+	return ok
+}
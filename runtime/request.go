@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GraphqlRequest is the decoded form of an incoming GraphQL HTTP request,
+// following the same shape used across the ecosystem (apollo-server,
+// express-graphql, ...).
+type GraphqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+// parseRequest decodes a GraphqlRequest from r. POST requests are expected
+// to carry a JSON body; GET requests read the same fields from the query
+// string, with variables and extensions passed as JSON-encoded strings.
+func parseRequest(r *http.Request) (*GraphqlRequest, error) {
+	if r.Method == http.MethodGet {
+		return parseGetRequest(r)
+	}
+
+	req := &GraphqlRequest{}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func parseGetRequest(r *http.Request) (*GraphqlRequest, error) {
+	q := r.URL.Query()
+	req := &GraphqlRequest{
+		Query:         q.Get("query"),
+		OperationName: q.Get("operationName"),
+	}
+
+	if v := q.Get("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+			return nil, err
+		}
+	}
+	if e := q.Get("extensions"); e != "" {
+		if err := json.Unmarshal([]byte(e), &req.Extensions); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
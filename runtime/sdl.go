@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// builtinScalars are the scalars the GraphQL spec defines; printSchema
+// doesn't redeclare them since every client already knows about them.
+var builtinScalars = map[string]bool{
+	"String":  true,
+	"Int":     true,
+	"Float":   true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// printSchema renders schema as SDL. graphql-go's printer package only
+// knows how to print parsed ast.Node values, not the Schema/Object/Field
+// values a SchemaConfig builds, so this walks the schema's TypeMap
+// directly instead.
+func printSchema(schema graphql.Schema) string {
+	names := make([]string, 0, len(schema.TypeMap()))
+	for name := range schema.TypeMap() {
+		if strings.HasPrefix(name, "__") || builtinScalars[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		printType(&b, schema.TypeMap()[name])
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func printType(b *strings.Builder, t graphql.Type) {
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		fmt.Fprintf(b, "scalar %s", t.Name())
+	case *graphql.Enum:
+		fmt.Fprintf(b, "enum %s {\n", t.Name())
+		for _, v := range t.Values() {
+			fmt.Fprintf(b, "  %s\n", v.Name)
+		}
+		b.WriteString("}")
+	case *graphql.Interface:
+		fmt.Fprintf(b, "interface %s {\n", t.Name())
+		printFields(b, t.Fields())
+		b.WriteString("}")
+	case *graphql.Union:
+		members := make([]string, 0, len(t.Types()))
+		for _, m := range t.Types() {
+			members = append(members, m.Name())
+		}
+		fmt.Fprintf(b, "union %s = %s", t.Name(), strings.Join(members, " | "))
+	case *graphql.InputObject:
+		fmt.Fprintf(b, "input %s {\n", t.Name())
+		names := make([]string, 0, len(t.Fields()))
+		for name := range t.Fields() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			f := t.Fields()[name]
+			fmt.Fprintf(b, "  %s: %s\n", name, f.Type.String())
+		}
+		b.WriteString("}")
+	case *graphql.Object:
+		fmt.Fprintf(b, "type %s", t.Name())
+		if len(t.Interfaces()) > 0 {
+			ifaces := make([]string, 0, len(t.Interfaces()))
+			for _, i := range t.Interfaces() {
+				ifaces = append(ifaces, i.Name())
+			}
+			fmt.Fprintf(b, " implements %s", strings.Join(ifaces, " & "))
+		}
+		b.WriteString(" {\n")
+		printFields(b, t.Fields())
+		b.WriteString("}")
+	}
+}
+
+// printFields renders a FieldDefinitionMap's entries in stable, sorted
+// order, including any arguments a field declares.
+func printFields(b *strings.Builder, fields graphql.FieldDefinitionMap) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fields[name]
+		if len(f.Args) == 0 {
+			fmt.Fprintf(b, "  %s: %s\n", name, f.Type.String())
+			continue
+		}
+		args := make([]string, 0, len(f.Args))
+		for _, arg := range f.Args {
+			args = append(args, fmt.Sprintf("%s: %s", arg.Name(), arg.Type.String()))
+		}
+		fmt.Fprintf(b, "  %s(%s): %s\n", name, strings.Join(args, ", "), f.Type.String())
+	}
+}
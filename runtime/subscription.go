@@ -0,0 +1,306 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"google.golang.org/grpc"
+)
+
+// graphqlWSSubprotocol is the subprotocol name used by the legacy
+// subscriptions-transport-ws. Its successor, graphql-transport-ws, keeps
+// connection_init/connection_ack and complete but renames start/data to
+// subscribe/next and adds ping/pong - both vocabularies are handled in
+// serveSubscription, switched on the subprotocol negotiated at Upgrade.
+const graphqlWSSubprotocol = "graphql-ws"
+
+// graphqlTransportWSSubprotocol is the subprotocol name used by the
+// graphql-ws (new protocol, package name graphql-transport-ws) client
+// library.
+const graphqlTransportWSSubprotocol = "graphql-transport-ws"
+
+// Message types exchanged over the websocket connection. start/data (legacy)
+// and subscribe/next (graphql-transport-ws) carry identical payload shapes
+// and are handled by the same cases below; ping/pong only exist in the
+// newer protocol.
+const (
+	gqlConnectionInit      = "connection_init"
+	gqlConnectionAck       = "connection_ack"
+	gqlConnectionError     = "connection_error"
+	gqlConnectionTerminate = "connection_terminate"
+	gqlStart               = "start"
+	gqlSubscribe           = "subscribe"
+	gqlStop                = "stop"
+	gqlData                = "data"
+	gqlNext                = "next"
+	gqlError               = "error"
+	gqlComplete            = "complete"
+	gqlPing                = "ping"
+	gqlPong                = "pong"
+)
+
+// GraphqlSubscriptionHandler is an optional interface a GraphqlHandler may
+// additionally implement in order to expose GraphQL subscriptions. Like the
+// sibling ComplexityHandler, it is deliberately parameterless: the resolver
+// already captured its *grpc.ClientConn when it was constructed in
+// Register<Service>GraphqlHandler, so GetSubscriptions needs no connection
+// argument of its own, matching what the generator emits.
+type GraphqlSubscriptionHandler interface {
+	GetSubscriptions() graphql.Fields
+}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type startPayload struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// defaultUpgrader is used when no upgrader has been configured via
+// WithWebsocketUpgrader. It accepts any origin, matching the permissive
+// default of the HTTP handler itself.
+var defaultUpgrader = &websocket.Upgrader{
+	CheckOrigin:  func(r *http.Request) bool { return true },
+	Subprotocols: []string{graphqlWSSubprotocol, "subscriptions-transport-ws", "graphql-transport-ws"},
+}
+
+// WithWebsocketUpgrader sets the *websocket.Upgrader used to promote
+// incoming /graphql requests to a GraphQL subscription connection. Call it
+// before serving traffic if you need custom origin checks, buffer sizes, etc.
+func (s *ServeMux) WithWebsocketUpgrader(u *websocket.Upgrader) *ServeMux {
+	s.wsUpgrader = u
+	return s
+}
+
+// isWebsocketUpgrade reports whether r is requesting a websocket upgrade.
+// It looks only at the Upgrade and Connection headers; the subprotocol is
+// negotiated afterwards by the upgrader.
+func isWebsocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// serveSubscription upgrades the connection and speaks the graphql-ws
+// protocol over it until the client disconnects or sends
+// connection_terminate.
+func (s *ServeMux) serveSubscription(schema graphql.Schema, w http.ResponseWriter, r *http.Request, ctx context.Context) {
+	upgrader := s.wsUpgrader
+	if upgrader == nil {
+		upgrader = defaultUpgrader
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// graphql-transport-ws renames the "data" frame to "next"; everything
+	// else about the two protocols' wire shapes is identical.
+	dataType := gqlData
+	if conn.Subprotocol() == graphqlTransportWSSubprotocol {
+		dataType = gqlNext
+	}
+
+	var mu sync.Mutex
+	send := func(msg wsMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		conn.WriteJSON(msg) // nolint: errcheck
+	}
+
+	subs := make(map[string]context.CancelFunc)
+	var subsMu sync.Mutex
+	defer func() {
+		subsMu.Lock()
+		for _, cancel := range subs {
+			cancel()
+		}
+		subsMu.Unlock()
+	}()
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		switch msg.Type {
+		case gqlConnectionInit:
+			if len(msg.Payload) > 0 {
+				var payload map[string]interface{}
+				if err := json.Unmarshal(msg.Payload, &payload); err == nil {
+					ctx = context.WithValue(ctx, connectionInitPayloadKey{}, payload)
+				}
+			}
+			send(wsMessage{Type: gqlConnectionAck})
+
+		case gqlStart, gqlSubscribe:
+			var payload startPayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				send(wsMessage{ID: msg.ID, Type: gqlError, Payload: mustMarshal(GraphqlError{Message: err.Error()})})
+				continue
+			}
+
+			// Create the cancelable context before opening the gRPC stream
+			// and resolve against it directly, so cancelling it on gqlStop
+			// cancels the stream itself instead of only the forwarding
+			// goroutine's select below.
+			subCtx, cancel := context.WithCancel(ctx)
+			fieldName, ch, err := s.subscribeField(schema, subCtx, r, payload.Query, payload.Variables)
+			if err != nil {
+				cancel()
+				send(wsMessage{ID: msg.ID, Type: gqlError, Payload: mustMarshal(GraphqlError{Message: err.Error()})})
+				continue
+			}
+
+			subsMu.Lock()
+			subs[msg.ID] = cancel
+			subsMu.Unlock()
+
+			id := msg.ID
+			go func() {
+				defer func() {
+					subsMu.Lock()
+					delete(subs, id)
+					subsMu.Unlock()
+				}()
+
+				for {
+					select {
+					case <-subCtx.Done():
+						return
+					case value, ok := <-ch:
+						if !ok {
+							send(wsMessage{ID: id, Type: gqlComplete})
+							return
+						}
+						send(wsMessage{ID: id, Type: dataType, Payload: mustMarshal(graphqlResponse{
+							Data: map[string]interface{}{fieldName: value},
+						})})
+					}
+				}
+			}()
+
+		case gqlStop, gqlComplete:
+			subsMu.Lock()
+			if cancel, ok := subs[msg.ID]; ok {
+				cancel()
+				delete(subs, msg.ID)
+			}
+			subsMu.Unlock()
+			send(wsMessage{ID: msg.ID, Type: gqlComplete})
+
+		case gqlPing:
+			send(wsMessage{Type: gqlPong})
+
+		case gqlConnectionTerminate:
+			return
+		}
+	}
+}
+
+// connectionInitPayloadKey is the context key under which the payload sent
+// with connection_init is stored, so that directive guards registered via
+// UseDirective and the subscription resolvers can read it from the
+// subscription's context. Middlewares registered via Use run once on the
+// upgrade request, before connection_init arrives, and do not see it.
+type connectionInitPayloadKey struct{}
+
+// ConnectionInitPayloadFromContext returns the payload sent by the client's
+// connection_init message, if any.
+func ConnectionInitPayloadFromContext(ctx context.Context) (map[string]interface{}, bool) {
+	payload, ok := ctx.Value(connectionInitPayloadKey{}).(map[string]interface{})
+	return payload, ok
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	b, _ := json.Marshal(v) // nolint: errcheck
+	return b
+}
+
+type graphqlResponse struct {
+	Data interface{} `json:"data"`
+}
+
+// subscribeField resolves the single top-level field of a subscription
+// operation and returns the channel its Resolve func produced. graphql-go
+// has no Subscribe phase of its own, so the generated resolver does both
+// jobs at once: Resolve opens the gRPC stream and hands back a
+// chan interface{} that this function then drains, one value per message
+// forwarded to the client.
+//
+// Before resolving, it runs the field's registered UseDirective guards
+// (the same @auth/@whitelist middlewares ServeHTTP applies to queries and
+// mutations) against ctx, so a subscription gets the same enforcement -
+// including whatever connection_init carried, since ctx is the one
+// serveSubscription populated from that message.
+func (s *ServeMux) subscribeField(schema graphql.Schema, ctx context.Context, r *http.Request, query string, variables map[string]interface{}) (string, chan interface{}, error) {
+	subscriptionType := schema.SubscriptionType()
+	if subscriptionType == nil {
+		return "", nil, fmt.Errorf("schema has no Subscription type")
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var field *ast.Field
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok || op.Operation != "subscription" || op.SelectionSet == nil {
+			continue
+		}
+		for _, sel := range op.SelectionSet.Selections {
+			if f, ok := sel.(*ast.Field); ok {
+				field = f
+				break
+			}
+		}
+	}
+	if field == nil {
+		return "", nil, fmt.Errorf("no subscription field found in operation")
+	}
+
+	name := field.Name.Value
+	fieldDef, ok := subscriptionType.Fields()[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown subscription field %q", name)
+	}
+
+	if directives, ok := s.graphQLMiddlewares[name]; ok {
+		for _, fn := range directives {
+			if err := fn(ctx, r, name); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	result, err := fieldDef.Resolve(graphql.ResolveParams{
+		Args:    resolveArguments(field.Arguments, variables),
+		Context: ctx,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	ch, ok := result.(chan interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("subscription field %q did not resolve to a channel", name)
+	}
+	return name, ch, nil
+}